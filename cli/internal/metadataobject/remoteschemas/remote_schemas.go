@@ -1,37 +1,104 @@
 package remoteschemas
 
 import (
-	"io/ioutil"
-	"os"
+	"bytes"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/hasura/graphql-engine/cli/v2"
 	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store"
+	_ "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store/gcs"
+	_ "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store/local"
+	_ "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store/s3"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	remoteSchemasDirectory string = "remote_schemas"
+	definitionFileName     string = "definition.yaml"
+	commentFileName        string = "comment.yaml"
+	permissionsDirectory   string = "permissions"
+	sdlFileExt             string = ".graphql"
+)
+
 type RemoteSchema struct {
-	Name       string      `yaml:"name"`
-	Definition interface{} `yaml:"definition"`
-	Comment    interface{} `yaml:"comment"`
-	Permission interface{} `yaml:"permissions"`
+	Name string `yaml:"name"`
+	// Definition is typed as yaml.MapSlice, not interface{}: yaml.v2 only
+	// preserves source key order for a mapping when the decode target's
+	// static type is exactly yaml.MapSlice. An interface{} field decodes a
+	// standalone file read (as buildSchema does, one file at a time) into a
+	// plain map[interface{}]interface{} instead, silently losing order and
+	// making "url"/"headers" lookups that expect yaml.MapSlice fail.
+	Definition  yaml.MapSlice            `yaml:"definition"`
+	Comment     interface{}              `yaml:"comment,omitempty"`
+	Permissions []RemoteSchemaPermission `yaml:"permissions,omitempty"`
 }
 
-func (r RemoteSchema) BaseDirectory() string {
-	panic("implement me")
+// RemoteSchemaPermission is a single per-role permission definition for a
+// remote schema, i.e. the contents of one
+// remote_schemas/<schema>/permissions/<role>.yaml file.
+type RemoteSchemaPermission struct {
+	Role string `yaml:"role"`
+	// Definition is yaml.MapSlice for the same reason as RemoteSchema.Definition.
+	Definition yaml.MapSlice `yaml:"definition"`
 }
 
 type SchemaDefinition struct {
 	Schema string `yaml:"schema"`
 }
 
+// Plan is the result of a dry-run Export: the would-be contents of every
+// file it would write, alongside a unified diff against what is currently
+// on the store.
+type Plan struct {
+	Files []PlannedFile
+}
+
+// PlannedFile describes a single file a real Export would write.
+type PlannedFile struct {
+	// Path is the file path as Export would report it, i.e. relative to the
+	// current working directory rather than to MetadataDir.
+	Path string
+	// Content is the full new content of the file.
+	Content []byte
+	// Diff is a unified diff against the file's current content on the
+	// store, empty if the file is unchanged or does not exist on the store
+	// yet (Content then holds the whole new file).
+	Diff string
+}
+
 type RemoteSchemaConfig struct {
 	MetadataDir string
 
+	// WithSDL enables the introspect-and-cache workflow: Export writes a
+	// remote_schemas/<name>.graphql snapshot obtained by introspecting the
+	// live endpoint, and Build fails loudly if a cached snapshot no longer
+	// matches the endpoint it was taken from. It is off by default since it
+	// requires network access to every remote schema endpoint.
+	WithSDL bool
+
+	// DryRun makes CreateFiles a no-op and makes Export return the files it
+	// would write without writing them to the store. Plan additionally diffs
+	// each file against its current content on the store.
+	DryRun bool
+
+	store  store.Store
 	logger *logrus.Logger
 }
 
+// New builds a RemoteSchemaConfig rooted at baseDir, which is almost always
+// the project's local metadata directory. baseDir may also be passed as a
+// "s3://..." or "gs://..." URI to persist remote schemas in object storage
+// instead, since store/s3 and store/gcs are registered alongside store/local
+// below - but nothing under this package decides that for itself. Getting a
+// URI into baseDir is a caller concern (CLI config/flag plumbing), not
+// something New or RemoteSchemaConfig do on their own.
 func New(ec *cli.ExecutionContext, baseDir string) *RemoteSchemaConfig {
 	return &RemoteSchemaConfig{
 		MetadataDir: baseDir,
@@ -43,70 +110,456 @@ func (r *RemoteSchemaConfig) Validate() error {
 	return nil
 }
 
-func (r *RemoteSchemaConfig) CreateFiles() error {
-	v := make([]interface{}, 0)
-	data, err := yaml.Marshal(v)
-	if err != nil {
-		return err
+// getStore lazily opens the backend addressed by MetadataDir. MetadataDir is
+// a plain filesystem path for most projects, in which case this resolves to
+// the local backend, but it may also be a URI like "s3://bucket/prefix" or
+// "gs://bucket/prefix" to persist remote schemas in object storage.
+func (r *RemoteSchemaConfig) getStore() (store.Store, error) {
+	if r.store == nil {
+		s, err := store.Open(r.MetadataDir)
+		if err != nil {
+			return nil, err
+		}
+		r.store = s
 	}
+	return r.store, nil
+}
 
-	path := filepath.Join(r.MetadataDir, r.Filename())
-	if err := os.MkdirAll(filepath.Dir(path), 0744); err != nil {
-		return err
+// CreateFiles scaffolds an empty remote_schemas directory. Individual remote
+// schemas are written to their own sub directory on export, there is no
+// longer a single root file to seed. Backends without a notion of empty
+// directories are left untouched.
+func (r *RemoteSchemaConfig) CreateFiles() error {
+	if r.DryRun {
+		return nil
 	}
-	err = ioutil.WriteFile(path, data, 0644)
+	s, err := r.getStore()
 	if err != nil {
 		return err
 	}
+	if scaffolder, ok := s.(store.DirScaffolder); ok {
+		return scaffolder.EnsureDir(r.DirectoryName())
+	}
 	return nil
 }
+
 func (r *RemoteSchemaConfig) Build(metadata *yaml.MapSlice) metadataobject.ErrParsingMetadataObject {
-	data, err := ioutil.ReadFile(filepath.Join(r.MetadataDir, r.Filename()))
+	s, err := r.getStore()
 	if err != nil {
 		return r.error(err)
 	}
-	item := yaml.MapItem{
-		Key: "remote_schemas",
-	}
-	var obj []yaml.MapSlice
-	err = yaml.Unmarshal(data, &obj)
+
+	names, filesByName, err := r.listSchemas(s)
 	if err != nil {
 		return r.error(err)
 	}
-	if len(obj) != 0 {
-		item.Value = obj
-		*metadata = append(*metadata, item)
+
+	var schemas []yaml.MapSlice
+	for _, name := range names {
+		schema, err := r.buildSchema(s, name, filesByName[name])
+		if err != nil {
+			return r.error(err, fmt.Sprintf("remote schema: %s", name))
+		}
+		schemas = append(schemas, schema)
+	}
+	if len(schemas) != 0 {
+		*metadata = append(*metadata, yaml.MapItem{Key: "remote_schemas", Value: schemas})
 	}
 	return nil
 }
 
+// listSchemas groups the files under the remote_schemas directory by the
+// name of the remote schema they belong to.
+func (r *RemoteSchemaConfig) listSchemas(s store.Store) ([]string, map[string][]string, error) {
+	paths, err := s.List(r.DirectoryName())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filesByName := map[string][]string{}
+	var names []string
+	for _, p := range paths {
+		rel, err := filepath.Rel(r.DirectoryName(), filepath.ToSlash(p))
+		if err != nil {
+			return nil, nil, err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		// remote_schemas/<name>.graphql SDL snapshots sit beside the schema
+		// directories; they are read by verifySDL, not grouped as a schema.
+		if len(parts) == 1 && strings.HasSuffix(parts[0], sdlFileExt) {
+			continue
+		}
+		name := parts[0]
+		if _, ok := filesByName[name]; !ok {
+			names = append(names, name)
+		}
+		filesByName[name] = append(filesByName[name], p)
+	}
+	sort.Strings(names)
+	return names, filesByName, nil
+}
+
+// buildSchema reads back the definition, comment and per-role permission
+// files of a single remote schema into a RemoteSchema, then re-assembles it
+// into the MapSlice representation expected under the top level
+// "remote_schemas" key.
+func (r *RemoteSchemaConfig) buildSchema(s store.Store, name string, files []string) (yaml.MapSlice, error) {
+	schemaDir := filepath.ToSlash(filepath.Join(r.DirectoryName(), name))
+	present := map[string]bool{}
+	for _, f := range files {
+		present[filepath.ToSlash(f)] = true
+	}
+
+	rs := RemoteSchema{Name: name}
+
+	definitionPath := filepath.ToSlash(filepath.Join(schemaDir, definitionFileName))
+	if !present[definitionPath] {
+		return nil, fmt.Errorf("%s not found", definitionPath)
+	}
+	if err := readYaml(s, definitionPath, &rs.Definition); err != nil {
+		return nil, err
+	}
+
+	if r.WithSDL {
+		if err := r.verifySDL(s, name, rs.Definition); err != nil {
+			return nil, err
+		}
+	}
+
+	commentPath := filepath.ToSlash(filepath.Join(schemaDir, commentFileName))
+	if present[commentPath] {
+		if err := readYaml(s, commentPath, &rs.Comment); err != nil {
+			return nil, err
+		}
+	}
+
+	permissionsDir := filepath.ToSlash(filepath.Join(schemaDir, permissionsDirectory))
+	for _, f := range files {
+		f = filepath.ToSlash(f)
+		if filepath.ToSlash(filepath.Dir(f)) != permissionsDir {
+			continue
+		}
+		var permission RemoteSchemaPermission
+		if err := readYaml(s, f, &permission); err != nil {
+			return nil, err
+		}
+		rs.Permissions = append(rs.Permissions, permission)
+	}
+
+	return remoteSchemaToMapSlice(rs)
+}
+
+// remoteSchemaToMapSlice renders rs through YAML and back to recover the
+// yaml.MapSlice representation Build assembles "remote_schemas" entries
+// from, preserving the struct's field order (name, definition, comment,
+// permissions).
+func remoteSchemaToMapSlice(rs RemoteSchema) (yaml.MapSlice, error) {
+	raw, err := yaml.Marshal(rs)
+	if err != nil {
+		return nil, err
+	}
+	var schema yaml.MapSlice
+	if err := yaml.Unmarshal(raw, &schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Export writes a remote_schemas/<name>/... file tree for every remote
+// schema in metadata. If r.DryRun is set, it returns what it would have
+// written without touching the store, the same way CreateFiles does for the
+// scaffolded directory; use Plan instead if a diff against the current
+// content is also needed.
 func (r *RemoteSchemaConfig) Export(metadata yaml.MapSlice) (map[string][]byte, metadataobject.ErrParsingMetadataObject) {
-	var remoteSchemas interface{}
+	schemas, err := remoteSchemasFromMetadata(metadata)
+	if err != nil {
+		return nil, r.error(err)
+	}
+
+	relFiles, err := r.planFiles(schemas)
+	if err != nil {
+		return nil, r.error(err)
+	}
+
+	var s store.Store
+	if !r.DryRun {
+		s, err = r.getStore()
+		if err != nil {
+			return nil, r.error(err)
+		}
+	}
+
+	files := map[string][]byte{}
+	for relPath, content := range relFiles {
+		if !r.DryRun {
+			if err := s.Write(relPath, content); err != nil {
+				return nil, r.error(err)
+			}
+		}
+		files[filepath.ToSlash(filepath.Join(r.MetadataDir, relPath))] = content
+	}
+	return files, nil
+}
+
+// Plan computes the files Export would write without touching the store,
+// pairing each one with a unified diff against what is there today. It lets
+// `metadata export` be previewed, e.g. in CI, before anything is mutated.
+func (r *RemoteSchemaConfig) Plan(metadata yaml.MapSlice) (Plan, metadataobject.ErrParsingMetadataObject) {
+	schemas, err := remoteSchemasFromMetadata(metadata)
+	if err != nil {
+		return Plan{}, r.error(err)
+	}
+
+	s, err := r.getStore()
+	if err != nil {
+		return Plan{}, r.error(err)
+	}
+
+	relFiles, err := r.planFiles(schemas)
+	if err != nil {
+		return Plan{}, r.error(err)
+	}
+
+	paths := make([]string, 0, len(relFiles))
+	for relPath := range relFiles {
+		paths = append(paths, relPath)
+	}
+	sort.Strings(paths)
+
+	plan := Plan{Files: make([]PlannedFile, 0, len(paths))}
+	for _, relPath := range paths {
+		content := relFiles[relPath]
+		var diff string
+		current, err := s.Read(relPath)
+		if err != nil {
+			if !errors.Is(err, store.ErrNotExist) {
+				return Plan{}, r.error(fmt.Errorf("reading current content of %s: %w", relPath, err))
+			}
+		} else {
+			diff = unifiedDiff(relPath, current, content)
+		}
+		plan.Files = append(plan.Files, PlannedFile{
+			Path:    filepath.ToSlash(filepath.Join(r.MetadataDir, relPath)),
+			Content: content,
+			Diff:    diff,
+		})
+	}
+	return plan, nil
+}
+
+// planFiles computes the contents of every file Export would write for the
+// given remote schemas, keyed by path relative to MetadataDir.
+func (r *RemoteSchemaConfig) planFiles(schemas []yaml.MapSlice) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, schema := range schemas {
+		name, schemaFiles, err := r.exportSchema(schema)
+		if err != nil {
+			return nil, err
+		}
+		if r.WithSDL {
+			definition, _ := mapSliceValue(schema, "definition")
+			sdlPath, sdl, err := r.exportSDL(name, definition)
+			if err != nil {
+				return nil, err
+			}
+			schemaFiles[sdlPath] = sdl
+		}
+		for relPath, content := range schemaFiles {
+			files[relPath] = content
+		}
+	}
+	return files, nil
+}
+
+// remoteSchemasFromMetadata extracts the remote_schemas list from a parsed
+// metadata document. Build assembles this list directly as []yaml.MapSlice,
+// but metadata decoded generically off the wire (e.g. by
+// projectmetadata.Handler.ExportMetadata's yaml.Decoder) represents it as
+// []interface{} of yaml.MapSlice, so both shapes have to be accepted.
+func remoteSchemasFromMetadata(metadata yaml.MapSlice) ([]yaml.MapSlice, error) {
+	var schemas []yaml.MapSlice
 	for _, item := range metadata {
 		k, ok := item.Key.(string)
 		if !ok || k != "remote_schemas" {
 			continue
 		}
-		remoteSchemas = item.Value
+		list, ok := asMapSliceList(item.Value)
+		if !ok {
+			return nil, fmt.Errorf("unable to cast remote_schemas object")
+		}
+		schemas = list
+	}
+	return schemas, nil
+}
+
+// exportSchema splits a single remote schema MapSlice into the contents of
+// its definition.yaml, comment.yaml and permissions/<role>.yaml files, keyed
+// by path relative to MetadataDir. schema is re-decoded into a RemoteSchema
+// rather than switched over by hand, so it doesn't matter whether it came
+// from Build (permissions as []yaml.MapSlice) or a generic decode off the
+// wire (permissions as []interface{} of yaml.MapSlice) - yaml.Unmarshal
+// normalizes both into the typed struct fields.
+func (r *RemoteSchemaConfig) exportSchema(schema yaml.MapSlice) (string, map[string][]byte, error) {
+	raw, err := yaml.Marshal(schema)
+	if err != nil {
+		return "", nil, err
 	}
-	if remoteSchemas == nil {
-		remoteSchemas = make([]interface{}, 0)
+	var rs RemoteSchema
+	if err := yaml.Unmarshal(raw, &rs); err != nil {
+		return "", nil, err
 	}
-	data, err := yaml.Marshal(remoteSchemas)
+	if rs.Name == "" {
+		return "", nil, fmt.Errorf("remote schema is missing a name")
+	}
+
+	schemaDir := filepath.ToSlash(filepath.Join(r.DirectoryName(), rs.Name))
+	files := map[string][]byte{}
+
+	definitionBytes, err := yaml.Marshal(rs.Definition)
 	if err != nil {
-		return nil, r.error(err)
+		return "", nil, err
+	}
+	files[filepath.ToSlash(filepath.Join(schemaDir, definitionFileName))] = definitionBytes
+
+	if rs.Comment != nil {
+		commentBytes, err := yaml.Marshal(rs.Comment)
+		if err != nil {
+			return "", nil, err
+		}
+		files[filepath.ToSlash(filepath.Join(schemaDir, commentFileName))] = commentBytes
+	}
+
+	for _, permission := range rs.Permissions {
+		if permission.Role == "" {
+			return "", nil, fmt.Errorf("remote schema permission is missing a role")
+		}
+		permissionBytes, err := yaml.Marshal(permission)
+		if err != nil {
+			return "", nil, err
+		}
+		files[filepath.ToSlash(filepath.Join(schemaDir, permissionsDirectory, permission.Role+".yaml"))] = permissionBytes
+	}
+
+	return rs.Name, files, nil
+}
+
+func permissionRole(permission yaml.MapSlice) (string, error) {
+	for _, item := range permission {
+		key, _ := item.Key.(string)
+		if key != "role" {
+			continue
+		}
+		role, ok := item.Value.(string)
+		if ok && role != "" {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("remote schema permission is missing a role")
+}
+
+// FilterByRoles returns a copy of metadata with every remote schema's
+// permissions array narrowed down to roles. The apply pipeline can call
+// this to push only the permissions a given role set owns, e.g. for
+// tenant- or environment-scoped rollouts where different roles are managed
+// by different teams. A nil or empty roles is a no-op.
+func (r *RemoteSchemaConfig) FilterByRoles(metadata yaml.MapSlice, roles []string) (yaml.MapSlice, error) {
+	if len(roles) == 0 {
+		return metadata, nil
+	}
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	filtered := make(yaml.MapSlice, len(metadata))
+	copy(filtered, metadata)
+	for i, item := range filtered {
+		k, ok := item.Key.(string)
+		if !ok || k != "remote_schemas" {
+			continue
+		}
+		schemas, ok := asMapSliceList(item.Value)
+		if !ok {
+			return nil, fmt.Errorf("unable to cast remote_schemas object")
+		}
+		filteredSchemas := make([]yaml.MapSlice, len(schemas))
+		for j, schema := range schemas {
+			filteredSchemas[j] = filterSchemaPermissions(schema, allowed)
+		}
+		filtered[i] = yaml.MapItem{Key: k, Value: filteredSchemas}
+	}
+	return filtered, nil
+}
+
+// filterSchemaPermissions returns a copy of schema with its permissions
+// array narrowed down to allowed roles, dropping the key entirely if none
+// remain.
+func filterSchemaPermissions(schema yaml.MapSlice, allowed map[string]bool) yaml.MapSlice {
+	filtered := make(yaml.MapSlice, 0, len(schema))
+	for _, item := range schema {
+		key, _ := item.Key.(string)
+		if key != "permissions" {
+			filtered = append(filtered, item)
+			continue
+		}
+		permissions, ok := asMapSliceList(item.Value)
+		if !ok {
+			filtered = append(filtered, item)
+			continue
+		}
+		var kept []yaml.MapSlice
+		for _, permission := range permissions {
+			if role, err := permissionRole(permission); err == nil && allowed[role] {
+				kept = append(kept, permission)
+			}
+		}
+		if len(kept) != 0 {
+			filtered = append(filtered, yaml.MapItem{Key: key, Value: kept})
+		}
+	}
+	return filtered
+}
+
+// asMapSliceList normalizes a decoded list-of-mappings value to
+// []yaml.MapSlice: Build assembles these directly as []yaml.MapSlice, while
+// a document parsed generically from disk or the Hasura API decodes them as
+// []interface{} of yaml.MapSlice.
+func asMapSliceList(v interface{}) ([]yaml.MapSlice, bool) {
+	switch list := v.(type) {
+	case []yaml.MapSlice:
+		return list, true
+	case []interface{}:
+		out := make([]yaml.MapSlice, 0, len(list))
+		for _, entry := range list {
+			ms, ok := entry.(yaml.MapSlice)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, ms)
+		}
+		return out, true
+	default:
+		return nil, false
 	}
-	return map[string][]byte{
-		filepath.ToSlash(filepath.Join(r.MetadataDir, r.Filename())): data,
-	}, nil
 }
 
+// GetFiles walks the remote_schemas directory tree and returns every file
+// that makes up the metadata object.
 func (r *RemoteSchemaConfig) GetFiles() ([]string, metadataobject.ErrParsingMetadataObject) {
-	rootFile := filepath.Join(r.BaseDirectory(), r.Filename())
-	files, err := metadataobject.DefaultGetFiles(rootFile)
+	s, err := r.getStore()
+	if err != nil {
+		return nil, r.error(err)
+	}
+	paths, err := s.List(r.DirectoryName())
 	if err != nil {
 		return nil, r.error(err)
 	}
+
+	files := make([]string, 0, len(paths))
+	for _, p := range paths {
+		files = append(files, filepath.Join(r.BaseDirectory(), p))
+	}
+	sort.Strings(files)
 	return files, nil
 }
 
@@ -126,9 +579,107 @@ func (r *RemoteSchemaConfig) Key() string {
 	return "remote_schemas"
 }
 
+// DirectoryName returns the directory remote schemas are stored under,
+// relative to the metadata directory.
+func (r *RemoteSchemaConfig) DirectoryName() string {
+	return remoteSchemasDirectory
+}
+
 func (r *RemoteSchemaConfig) Filename() string {
-	return "remote_schemas.yaml"
+	return remoteSchemasDirectory
 }
+
 func (r *RemoteSchemaConfig) error(err error, additionalContext ...string) metadataobject.ErrParsingMetadataObject {
 	return metadataobject.NewErrParsingMetadataObject(r, err, additionalContext...)
 }
+
+// exportSDL introspects the live endpoint addressed by definition and
+// returns the path/content pair for the resulting remote_schemas/<name>.graphql
+// snapshot, relative to MetadataDir.
+func (r *RemoteSchemaConfig) exportSDL(name string, definition interface{}) (string, []byte, error) {
+	url, headers, err := remoteSchemaEndpoint(definition)
+	if err != nil {
+		return "", nil, fmt.Errorf("remote schema %q: %w", name, err)
+	}
+	sdl, err := introspectSDL(url, headers)
+	if err != nil {
+		return "", nil, fmt.Errorf("remote schema %q: %w", name, err)
+	}
+	path := filepath.ToSlash(filepath.Join(r.DirectoryName(), name+sdlFileExt))
+	return path, []byte(sdl), nil
+}
+
+// verifySDL re-introspects the live endpoint for a remote schema and fails
+// if it has drifted from the remote_schemas/<name>.graphql snapshot taken at
+// the last export, so breaking upstream changes show up as build errors
+// instead of being silently picked up.
+func (r *RemoteSchemaConfig) verifySDL(s store.Store, name string, definition interface{}) error {
+	path := filepath.ToSlash(filepath.Join(r.DirectoryName(), name+sdlFileExt))
+	cached, err := s.Read(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	url, headers, err := remoteSchemaEndpoint(definition)
+	if err != nil {
+		return fmt.Errorf("remote schema %q: %w", name, err)
+	}
+	live, err := introspectSDL(url, headers)
+	if err != nil {
+		return fmt.Errorf("remote schema %q: %w", name, err)
+	}
+
+	if strings.TrimSpace(string(cached)) != strings.TrimSpace(live) {
+		return fmt.Errorf("%s: stored SDL no longer matches the live endpoint, re-run metadata export to pick up the change", path)
+	}
+	return nil
+}
+
+// unifiedDiff renders a unified diff between before and after, labelled with
+// path, or the empty string if the contents are identical.
+func unifiedDiff(path string, before, after []byte) string {
+	if bytes.Equal(before, after) {
+		return ""
+	}
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: path,
+		ToFile:   path,
+		Context:  3,
+	}
+	text, _ := difflib.GetUnifiedDiffString(diff)
+	return text
+}
+
+func readYaml(s store.Store, path string, out interface{}) error {
+	data, err := s.Read(path)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, out)
+}
+
+// mapSliceValue looks up key in a decoded yaml.MapSlice mapping.
+func mapSliceValue(ms interface{}, key string) (interface{}, bool) {
+	slice, ok := ms.(yaml.MapSlice)
+	if !ok {
+		return nil, false
+	}
+	for _, item := range slice {
+		if k, ok := item.Key.(string); ok && k == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// mapSliceString is mapSliceValue for the common case of a string-valued key.
+func mapSliceString(ms interface{}, key string) (string, bool) {
+	v, ok := mapSliceValue(ms, key)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
@@ -0,0 +1,394 @@
+package remoteschemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store/local"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// serverShapedMetadata decodes rawMetadata the same way metadata exported by
+// the server is decoded in practice (a generic yaml.Decoder into a
+// yaml.MapSlice), rather than hand-building the yaml.MapSlice/[]yaml.MapSlice
+// values in Go the way Build does. Nested list-of-maps end up as
+// []interface{} of yaml.MapSlice under this path, which is the shape that
+// tripped up the original []yaml.MapSlice-only type assertions.
+func serverShapedMetadata(t *testing.T, rawMetadata string) yaml.MapSlice {
+	t.Helper()
+	var metadata yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(rawMetadata), &metadata); err != nil {
+		t.Fatalf("unmarshalling test metadata: %v", err)
+	}
+	return metadata
+}
+
+func newTestConfig(t *testing.T) *RemoteSchemaConfig {
+	t.Helper()
+	return &RemoteSchemaConfig{
+		MetadataDir: t.TempDir(),
+		logger:      logrus.New(),
+	}
+}
+
+func TestExportServerShapedMetadataWithPermissions(t *testing.T) {
+	metadata := serverShapedMetadata(t, `
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: https://example.com/graphql
+      timeout_seconds: 60
+    comment: an example remote schema
+    permissions:
+      - role: admin
+        definition:
+          schema: "type Query { foo: String }"
+      - role: user
+        definition:
+          schema: "type Query { foo: String }"
+`)
+
+	r := newTestConfig(t)
+	files, err := r.Export(metadata)
+	if err != nil {
+		t.Fatalf("Export returned an error on server-shaped metadata: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(r.MetadataDir, "remote_schemas", "my-remote-schema", "definition.yaml"),
+		filepath.Join(r.MetadataDir, "remote_schemas", "my-remote-schema", "comment.yaml"),
+		filepath.Join(r.MetadataDir, "remote_schemas", "my-remote-schema", "permissions", "admin.yaml"),
+		filepath.Join(r.MetadataDir, "remote_schemas", "my-remote-schema", "permissions", "user.yaml"),
+	}
+	for _, path := range want {
+		if _, ok := files[path]; !ok {
+			t.Errorf("Export did not write expected file %s (wrote: %v)", path, mapKeys(files))
+		}
+	}
+}
+
+func TestExportBuildOutputWithPermissions(t *testing.T) {
+	// Build assembles permissions as []yaml.MapSlice directly, a different
+	// shape than the server's generic decode. Export must accept this shape
+	// too, since apply pipelines commonly Build from disk and Export back.
+	schema := yaml.MapSlice{
+		{Key: "name", Value: "my-remote-schema"},
+		{Key: "definition", Value: yaml.MapSlice{{Key: "url", Value: "https://example.com/graphql"}}},
+		{Key: "permissions", Value: []yaml.MapSlice{
+			{{Key: "role", Value: "admin"}, {Key: "definition", Value: yaml.MapSlice{{Key: "schema", Value: "type Query { foo: String }"}}}},
+		}},
+	}
+	metadata := yaml.MapSlice{{Key: "remote_schemas", Value: []yaml.MapSlice{schema}}}
+
+	r := newTestConfig(t)
+	files, err := r.Export(metadata)
+	if err != nil {
+		t.Fatalf("Export returned an error on Build-shaped metadata: %v", err)
+	}
+
+	path := filepath.Join(r.MetadataDir, "remote_schemas", "my-remote-schema", "permissions", "admin.yaml")
+	if _, ok := files[path]; !ok {
+		t.Errorf("Export did not write expected file %s (wrote: %v)", path, mapKeys(files))
+	}
+}
+
+func TestExportBuildRoundTrip(t *testing.T) {
+	metadata := serverShapedMetadata(t, `
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: https://example.com/graphql
+    permissions:
+      - role: admin
+        definition:
+          schema: "type Query { foo: String }"
+`)
+
+	r := newTestConfig(t)
+	if _, err := r.Export(metadata); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var built yaml.MapSlice
+	if err := r.Build(&built); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	filtered, err := r.FilterByRoles(built, []string{"admin"})
+	if err != nil {
+		t.Fatalf("FilterByRoles: %v", err)
+	}
+	if len(filtered) != len(built) {
+		t.Fatalf("FilterByRoles dropped an unrelated metadata key")
+	}
+}
+
+func TestExportDryRunDoesNotTouchStore(t *testing.T) {
+	metadata := serverShapedMetadata(t, `
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: https://example.com/graphql
+`)
+
+	r := newTestConfig(t)
+	r.DryRun = true
+	files, err := r.Export(metadata)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	path := filepath.Join(r.MetadataDir, "remote_schemas", "my-remote-schema", "definition.yaml")
+	if _, ok := files[path]; !ok {
+		t.Fatalf("Export did not report %s among the files it would write", path)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Export with DryRun set wrote %s to disk, want no-op", path)
+	}
+}
+
+func TestPlanReportsNewFilesAndDiffsExistingOnes(t *testing.T) {
+	r := newTestConfig(t)
+
+	metadata := serverShapedMetadata(t, `
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: https://example.com/graphql
+`)
+	plan, err := r.Plan(metadata)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	for _, f := range plan.Files {
+		if strings.HasSuffix(f.Path, definitionFileName) && f.Diff != "" {
+			t.Fatalf("expected no diff for a file that doesn't exist yet, got: %s", f.Diff)
+		}
+	}
+
+	if _, err := r.Export(metadata); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	changed := serverShapedMetadata(t, `
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: https://example.com/graphql-v2
+`)
+	plan, err = r.Plan(changed)
+	if err != nil {
+		t.Fatalf("Plan after Export: %v", err)
+	}
+	var found bool
+	for _, f := range plan.Files {
+		if strings.HasSuffix(f.Path, definitionFileName) {
+			found = true
+			if f.Diff == "" {
+				t.Fatalf("expected a diff for a changed file, got none")
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("Plan did not include %s", definitionFileName)
+	}
+}
+
+func TestPlanSurfacesReadErrorsOtherThanNotExist(t *testing.T) {
+	r := newTestConfig(t)
+
+	// Replace the definition.yaml path with a directory so a subsequent Read
+	// fails for a reason other than not-exist.
+	badPath := filepath.Join(r.MetadataDir, "remote_schemas", "my-remote-schema", "definition.yaml")
+	if err := os.MkdirAll(badPath, 0744); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	metadata := serverShapedMetadata(t, `
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: https://example.com/graphql
+`)
+	if _, err := r.Plan(metadata); err == nil {
+		t.Fatalf("expected Plan to surface the read error instead of treating it as a new file")
+	}
+}
+
+func TestExportBuildTypedStructRoundTrip(t *testing.T) {
+	r := newTestConfig(t)
+	metadata := serverShapedMetadata(t, `
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: https://example.com/graphql
+    comment: an example remote schema
+    permissions:
+      - role: admin
+        definition:
+          schema: "type Query { foo: String }"
+`)
+	if _, err := r.Export(metadata); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var built yaml.MapSlice
+	if err := r.Build(&built); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	schemas, err := remoteSchemasFromMetadata(built)
+	if err != nil {
+		t.Fatalf("remoteSchemasFromMetadata: %v", err)
+	}
+	if len(schemas) != 1 {
+		t.Fatalf("expected 1 remote schema, got %d", len(schemas))
+	}
+
+	raw, err := yaml.Marshal(schemas[0])
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var rs RemoteSchema
+	if err := yaml.Unmarshal(raw, &rs); err != nil {
+		t.Fatalf("Unmarshal into RemoteSchema: %v", err)
+	}
+	if rs.Name != "my-remote-schema" {
+		t.Errorf("Name = %q, want my-remote-schema", rs.Name)
+	}
+	if rs.Comment != "an example remote schema" {
+		t.Errorf("Comment = %v, want the exported comment", rs.Comment)
+	}
+	if len(rs.Permissions) != 1 || rs.Permissions[0].Role != "admin" {
+		t.Errorf("Permissions = %+v, want a single admin permission", rs.Permissions)
+	}
+}
+
+func TestExportBuildPreservesDefinitionKeyOrder(t *testing.T) {
+	// forward_client_headers sorts before timeout_seconds sorts before url,
+	// so a naive map[interface{}]interface{} round trip (which yaml.v2
+	// marshals with sorted keys) would visibly reorder this definition.
+	// Source order here is deliberately not alphabetical.
+	r := newTestConfig(t)
+	metadata := serverShapedMetadata(t, `
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: https://example.com/graphql
+      timeout_seconds: 60
+      forward_client_headers: true
+`)
+	if _, err := r.Export(metadata); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var built yaml.MapSlice
+	if err := r.Build(&built); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if _, err := r.Export(built); err != nil {
+		t.Fatalf("Export after Build: %v", err)
+	}
+
+	path := filepath.Join(r.MetadataDir, "remote_schemas", "my-remote-schema", definitionFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	urlIdx := strings.Index(string(data), "url:")
+	timeoutIdx := strings.Index(string(data), "timeout_seconds:")
+	headersIdx := strings.Index(string(data), "forward_client_headers:")
+	if urlIdx == -1 || timeoutIdx == -1 || headersIdx == -1 {
+		t.Fatalf("definition.yaml is missing an expected key, got:\n%s", data)
+	}
+	if !(urlIdx < timeoutIdx && timeoutIdx < headersIdx) {
+		t.Fatalf("Export->Build->Export reordered definition.yaml's keys, got:\n%s", data)
+	}
+}
+
+// introspectionServer serves a minimal introspection response with a single
+// query field named field, letting a test vary the remote schema's shape
+// between an Export that snapshots it and a later Build that re-verifies it.
+func introspectionServer(t *testing.T, field string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		result := introspectionResponse{}
+		result.Data.Schema.QueryType = &introspectionNamedRef{Name: "Query"}
+		result.Data.Schema.Types = []introspectionType{
+			{Kind: "OBJECT", Name: "Query", Fields: []introspectionField{
+				{Name: field, Type: introspectionTypeRef{Kind: "SCALAR", Name: "String"}},
+			}},
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	}))
+}
+
+func TestBuildWithSDLDetectsNoDriftAndDrift(t *testing.T) {
+	srv := introspectionServer(t, "foo")
+	defer srv.Close()
+
+	r := newTestConfig(t)
+	r.WithSDL = true
+	metadata := serverShapedMetadata(t, fmt.Sprintf(`
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: %s
+`, srv.URL))
+	if _, err := r.Export(metadata); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var built yaml.MapSlice
+	if err := r.Build(&built); err != nil {
+		t.Fatalf("Build should succeed when the live schema matches the cached SDL snapshot: %v", err)
+	}
+
+	srv.Close()
+	drifted := introspectionServer(t, "bar")
+	defer drifted.Close()
+
+	r2 := newTestConfig(t)
+	r2.MetadataDir = r.MetadataDir
+	r2.WithSDL = true
+	driftedMetadata := serverShapedMetadata(t, fmt.Sprintf(`
+remote_schemas:
+  - name: my-remote-schema
+    definition:
+      url: %s
+`, drifted.URL))
+	if _, err := r2.Export(driftedMetadata); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	// Restore the stale snapshot so Build compares against outdated SDL,
+	// simulating the endpoint changing after the last `metadata export`.
+	snapshotPath := filepath.Join(r2.MetadataDir, "remote_schemas", "my-remote-schema"+sdlFileExt)
+	if err := os.WriteFile(snapshotPath, []byte("type Query {\n  foo: String\n}\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var built2 yaml.MapSlice
+	err := r2.Build(&built2)
+	if err == nil {
+		t.Fatal("expected Build to fail when the live schema has drifted from the cached SDL snapshot")
+	}
+	if !strings.Contains(err.Error(), "no longer matches the live endpoint") {
+		t.Fatalf("unexpected Build error: %v", err)
+	}
+}
+
+func mapKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,279 @@
+package remoteschemas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// introspectionTimeout bounds how long introspectSDL waits on a single
+// remote schema endpoint, so one slow or unreachable endpoint can't hang
+// metadata build/export indefinitely.
+const introspectionTimeout = 30 * time.Second
+
+// introspectionQuery is the subset of the standard GraphQL introspection
+// query needed to reconstruct an SDL rendering of a schema: every named
+// type, its fields/arguments/enum values, and the types it implements.
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+  }
+}
+fragment FullType on __Type {
+  kind
+  name
+  fields(includeDeprecated: true) {
+    name
+    args { ...InputValue }
+    type { ...TypeRef }
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue {
+  name
+  type { ...TypeRef }
+}
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType {
+    kind
+    name
+    ofType {
+      kind
+      name
+      ofType {
+        kind
+        name
+        ofType { kind name ofType { kind name ofType { kind name } } }
+      }
+    }
+  }
+}
+`
+
+type introspectionResponse struct {
+	Data struct {
+		Schema struct {
+			QueryType        *introspectionNamedRef `json:"queryType"`
+			MutationType     *introspectionNamedRef `json:"mutationType"`
+			SubscriptionType *introspectionNamedRef `json:"subscriptionType"`
+			Types            []introspectionType    `json:"types"`
+		} `json:"__schema"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type introspectionNamedRef struct {
+	Name string `json:"name"`
+}
+
+type introspectionType struct {
+	Kind          string                  `json:"kind"`
+	Name          string                  `json:"name"`
+	Fields        []introspectionField    `json:"fields"`
+	InputFields   []introspectionInput    `json:"inputFields"`
+	Interfaces    []introspectionTypeRef  `json:"interfaces"`
+	EnumValues    []introspectionNamedRef `json:"enumValues"`
+	PossibleTypes []introspectionTypeRef  `json:"possibleTypes"`
+}
+
+type introspectionField struct {
+	Name string               `json:"name"`
+	Args []introspectionInput `json:"args"`
+	Type introspectionTypeRef `json:"type"`
+}
+
+type introspectionInput struct {
+	Name string               `json:"name"`
+	Type introspectionTypeRef `json:"type"`
+}
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+func (t introspectionTypeRef) String() string {
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	default:
+		return t.Name
+	}
+}
+
+// builtinScalars are part of the GraphQL spec and are never printed.
+var builtinScalars = map[string]bool{
+	"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true,
+}
+
+// introspectSDL runs the introspection query against url and renders the
+// result as SDL, ordering types alphabetically so the output is diffable.
+func introspectSDL(url string, headers map[string]string) (string, error) {
+	body, err := json.Marshal(map[string]string{"query": introspectionQuery})
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), introspectionTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("introspecting %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	var result introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding introspection response from %q: %w", url, err)
+	}
+	if len(result.Errors) != 0 {
+		return "", fmt.Errorf("introspecting %q: %s", url, result.Errors[0].Message)
+	}
+
+	return printSDL(result), nil
+}
+
+// printSDL renders the named types of an introspected schema as SDL.
+func printSDL(result introspectionResponse) string {
+	types := make([]introspectionType, 0, len(result.Data.Schema.Types))
+	for _, t := range result.Data.Schema.Types {
+		if strings.HasPrefix(t.Name, "__") || builtinScalars[t.Name] {
+			continue
+		}
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+
+	var b strings.Builder
+	if q := result.Data.Schema.QueryType; q != nil {
+		fmt.Fprintf(&b, "schema {\n  query: %s\n", q.Name)
+		if m := result.Data.Schema.MutationType; m != nil {
+			fmt.Fprintf(&b, "  mutation: %s\n", m.Name)
+		}
+		if sub := result.Data.Schema.SubscriptionType; sub != nil {
+			fmt.Fprintf(&b, "  subscription: %s\n", sub.Name)
+		}
+		b.WriteString("}\n\n")
+	}
+
+	for _, t := range types {
+		printType(&b, t)
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func printType(b *strings.Builder, t introspectionType) {
+	switch t.Kind {
+	case "SCALAR":
+		fmt.Fprintf(b, "scalar %s\n\n", t.Name)
+	case "ENUM":
+		fmt.Fprintf(b, "enum %s {\n", t.Name)
+		for _, v := range t.EnumValues {
+			fmt.Fprintf(b, "  %s\n", v.Name)
+		}
+		b.WriteString("}\n\n")
+	case "INPUT_OBJECT":
+		fmt.Fprintf(b, "input %s {\n", t.Name)
+		for _, f := range t.InputFields {
+			fmt.Fprintf(b, "  %s: %s\n", f.Name, f.Type.String())
+		}
+		b.WriteString("}\n\n")
+	case "OBJECT", "INTERFACE":
+		keyword := "type"
+		if t.Kind == "INTERFACE" {
+			keyword = "interface"
+		}
+		implements := ""
+		if len(t.Interfaces) != 0 {
+			names := make([]string, len(t.Interfaces))
+			for i, iface := range t.Interfaces {
+				names[i] = iface.Name
+			}
+			implements = " implements " + strings.Join(names, " & ")
+		}
+		fmt.Fprintf(b, "%s %s%s {\n", keyword, t.Name, implements)
+		for _, f := range t.Fields {
+			args := ""
+			if len(f.Args) != 0 {
+				parts := make([]string, len(f.Args))
+				for i, a := range f.Args {
+					parts[i] = fmt.Sprintf("%s: %s", a.Name, a.Type.String())
+				}
+				args = "(" + strings.Join(parts, ", ") + ")"
+			}
+			fmt.Fprintf(b, "  %s%s: %s\n", f.Name, args, f.Type.String())
+		}
+		b.WriteString("}\n\n")
+	case "UNION":
+		names := make([]string, len(t.PossibleTypes))
+		for i, p := range t.PossibleTypes {
+			names[i] = p.Name
+		}
+		fmt.Fprintf(b, "union %s = %s\n\n", t.Name, strings.Join(names, " | "))
+	}
+}
+
+// remoteSchemaEndpoint extracts the URL and headers a request to the remote
+// schema should carry, from the decoded "definition" section of a
+// remote_schemas entry.
+func remoteSchemaEndpoint(definition interface{}) (string, map[string]string, error) {
+	url, _ := mapSliceString(definition, "url")
+	if url == "" {
+		if fromEnv, _ := mapSliceString(definition, "url_from_env"); fromEnv != "" {
+			url = os.Getenv(fromEnv)
+		}
+	}
+	if url == "" {
+		return "", nil, fmt.Errorf("remote schema definition is missing url/url_from_env")
+	}
+
+	headers := map[string]string{}
+	if raw, ok := mapSliceValue(definition, "headers"); ok {
+		list, ok := raw.([]interface{})
+		if !ok {
+			return "", nil, fmt.Errorf("remote schema definition has malformed headers")
+		}
+		for _, entry := range list {
+			name, _ := mapSliceString(entry, "name")
+			if name == "" {
+				continue
+			}
+			value, _ := mapSliceString(entry, "value")
+			if value == "" {
+				if fromEnv, _ := mapSliceString(entry, "value_from_env"); fromEnv != "" {
+					value = os.Getenv(fromEnv)
+				}
+			}
+			headers[name] = value
+		}
+	}
+	return url, headers, nil
+}
@@ -0,0 +1,58 @@
+package remoteschemas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrintSDLOrdersTypesAlphabeticallyAndSkipsBuiltins(t *testing.T) {
+	result := introspectionResponse{}
+	result.Data.Schema.QueryType = &introspectionNamedRef{Name: "Query"}
+	result.Data.Schema.Types = []introspectionType{
+		{Kind: "OBJECT", Name: "Zebra", Fields: []introspectionField{
+			{Name: "id", Type: introspectionTypeRef{Kind: "NON_NULL", OfType: &introspectionTypeRef{Kind: "SCALAR", Name: "ID"}}},
+		}},
+		{Kind: "SCALAR", Name: "String"},
+		{Kind: "OBJECT", Name: "Apple"},
+		{Kind: "OBJECT", Name: "__Internal"},
+	}
+
+	sdl := printSDL(result)
+
+	if i, j := strings.Index(sdl, "type Apple"), strings.Index(sdl, "type Zebra"); i == -1 || j == -1 || i > j {
+		t.Fatalf("expected Apple to print before Zebra, got:\n%s", sdl)
+	}
+	if strings.Contains(sdl, "String") {
+		t.Errorf("builtin scalar String should not be printed, got:\n%s", sdl)
+	}
+	if strings.Contains(sdl, "__Internal") {
+		t.Errorf("introspection type __Internal should not be printed, got:\n%s", sdl)
+	}
+	if !strings.Contains(sdl, "id: ID!") {
+		t.Errorf("expected non-null field rendering, got:\n%s", sdl)
+	}
+}
+
+func TestIntrospectSDLUsesRequestContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Hasura-Admin-Secret") != "secret" {
+			t.Errorf("expected custom header to be forwarded")
+		}
+		if req.Context().Err() != nil {
+			t.Errorf("request context already done: %v", req.Context().Err())
+		}
+		_ = json.NewEncoder(w).Encode(introspectionResponse{})
+	}))
+	defer srv.Close()
+
+	sdl, err := introspectSDL(srv.URL, map[string]string{"X-Hasura-Admin-Secret": "secret"})
+	if err != nil {
+		t.Fatalf("introspectSDL: %v", err)
+	}
+	if sdl != "\n" {
+		t.Errorf("expected empty schema to render as a blank document, got %q", sdl)
+	}
+}
@@ -0,0 +1,100 @@
+// Package store provides a pluggable abstraction over where metadata objects
+// are persisted. Metadata objects historically assumed a local checkout on
+// disk; Store lets them read from and write to any backend (local disk, S3,
+// GCS, ...) addressed by a URI, the same way migrate/source selects a
+// migration source driver by URL scheme.
+package store
+
+import (
+	"errors"
+	"fmt"
+	nurl "net/url"
+	"sync"
+	"unicode"
+)
+
+var storesMu sync.RWMutex
+var stores = make(map[string]Store)
+
+// ErrNotExist is the error Read must wrap (via fmt.Errorf's %w, so
+// errors.Is(err, ErrNotExist) succeeds) when path does not exist on the
+// store. Callers that need to tell "nothing written yet" apart from a
+// genuine read failure, such as Plan computing a diff against the current
+// content, rely on this rather than treating every Read error as not-found.
+var ErrNotExist = errors.New("store: object does not exist")
+
+// Store is the interface every storage backend must implement.
+//
+// How to implement a storage backend?
+//   1. Implement this interface.
+//   2. Add a function named `New` which accepts a URI and returns a Store.
+//   3. Call Register in init().
+//
+// Guidelines:
+//   * All configuration input must come from the URI string passed to Open().
+//   * Paths passed to Read/Write/List/Delete are always relative to the root
+//     addressed by the URI the Store was opened with.
+type Store interface {
+	// Open returns a new store instance configured with parameters coming
+	// from the URI. Callers will call this function only once per instance.
+	Open(uri string) (Store, error)
+
+	// Read returns the contents stored at path, relative to the store root.
+	// If path does not exist, the returned error must wrap ErrNotExist.
+	Read(path string) ([]byte, error)
+
+	// Write persists data at path, relative to the store root, creating any
+	// intermediate directories/prefixes required.
+	Write(path string, data []byte) error
+
+	// List returns every path stored under prefix, relative to the store root.
+	List(prefix string) ([]string, error)
+
+	// Delete removes the object stored at path, relative to the store root.
+	Delete(path string) error
+}
+
+// DirScaffolder is optionally implemented by stores that have a notion of
+// empty directories (eg. the local filesystem). Object storage backends have
+// no such notion, so callers should treat a failed type assertion as a no-op.
+type DirScaffolder interface {
+	EnsureDir(path string) error
+}
+
+// Open returns a new store instance for the given URI, eg:
+// "metadata", "file://./metadata", "s3://bucket/prefix" or "gs://bucket/prefix".
+func Open(uri string) (Store, error) {
+	scheme := "file"
+	if u, err := nurl.Parse(uri); err == nil && u.Scheme != "" && !isWindowsDriveLetter(u.Scheme) {
+		scheme = u.Scheme
+	}
+
+	storesMu.RLock()
+	d, ok := stores[scheme]
+	storesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("store: unknown backend %q (forgotten import?)", scheme)
+	}
+	return d.Open(uri)
+}
+
+// isWindowsDriveLetter reports whether scheme is actually a Windows drive
+// letter (eg. "c" from "C:\Users\foo\metadata") that net/url mistook for a
+// URI scheme, rather than a real scheme like "s3" or "gs" - every registered
+// backend scheme is longer than one character.
+func isWindowsDriveLetter(scheme string) bool {
+	return len(scheme) == 1 && unicode.IsLetter(rune(scheme[0]))
+}
+
+// Register globally registers a storage backend under the given URI scheme.
+func Register(scheme string, store Store) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if store == nil {
+		panic("store: Register store is nil")
+	}
+	if _, dup := stores[scheme]; dup {
+		panic("store: Register called twice for backend " + scheme)
+	}
+	stores[scheme] = store
+}
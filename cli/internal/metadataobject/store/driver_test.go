@@ -0,0 +1,61 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store"
+	_ "github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store/local"
+)
+
+func TestLocalRoundTripAndErrNotExist(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if _, err := s.Read("does/not/exist.yaml"); !errors.Is(err, store.ErrNotExist) {
+		t.Fatalf("Read of a missing path: got %v, want an error wrapping store.ErrNotExist", err)
+	}
+
+	if err := s.Write("a/b/c.yaml", []byte("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	data, err := s.Read("a/b/c.yaml")
+	if err != nil {
+		t.Fatalf("Read after Write: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("Read after Write = %q, want %q", data, "hello")
+	}
+
+	paths, err := s.List("a")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "a/b/c.yaml" {
+		t.Fatalf("List = %v, want [a/b/c.yaml]", paths)
+	}
+
+	if err := s.Delete("a/b/c.yaml"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Read("a/b/c.yaml"); !errors.Is(err, store.ErrNotExist) {
+		t.Fatalf("Read after Delete: got %v, want an error wrapping store.ErrNotExist", err)
+	}
+}
+
+func TestOpenUnknownScheme(t *testing.T) {
+	if _, err := store.Open("unknownscheme://bucket/prefix"); err == nil {
+		t.Fatal("expected Open to fail for a scheme with no registered backend")
+	}
+}
+
+func TestOpenTreatsWindowsDriveLetterAsLocalPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, uri := range []string{dir, `C:\Users\foo\metadata`, "C:/Users/foo/metadata"} {
+		if _, err := store.Open(uri); err != nil {
+			t.Fatalf("Open(%q): %v, want the local backend (single-letter scheme is a Windows drive letter, not a URI scheme)", uri, err)
+		}
+	}
+}
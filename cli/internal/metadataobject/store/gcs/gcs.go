@@ -0,0 +1,119 @@
+// Package gcs implements a store.Store backed by a Google Cloud Storage
+// bucket, addressed by a URI of the form gs://bucket/prefix.
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	nurl "net/url"
+	"strings"
+
+	gcstorage "cloud.google.com/go/storage"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store"
+	"google.golang.org/api/iterator"
+)
+
+type GCS struct {
+	bucket string
+	prefix string
+
+	client *gcstorage.Client
+}
+
+func init() {
+	store.Register("gs", &GCS{})
+}
+
+func New(uri string) (*GCS, error) {
+	u, err := nurl.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("gcs store: %q is missing a bucket name", uri)
+	}
+
+	client, err := gcstorage.NewClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("gcs store: creating client: %w", err)
+	}
+
+	return &GCS{
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+		client: client,
+	}, nil
+}
+
+func (g *GCS) Open(uri string) (store.Store, error) {
+	return New(uri)
+}
+
+func (g *GCS) object(path string) *gcstorage.ObjectHandle {
+	return g.client.Bucket(g.bucket).Object(g.key(path))
+}
+
+func (g *GCS) key(path string) string {
+	if g.prefix == "" {
+		return path
+	}
+	return g.prefix + "/" + path
+}
+
+func (g *GCS) Read(path string) ([]byte, error) {
+	ctx := context.Background()
+	r, err := g.object(path).NewReader(ctx)
+	if err != nil {
+		if err == gcstorage.ErrObjectNotExist {
+			return nil, fmt.Errorf("gcs store: reading %q: %w", path, store.ErrNotExist)
+		}
+		return nil, fmt.Errorf("gcs store: reading %q: %w", path, err)
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (g *GCS) Write(path string, data []byte) error {
+	ctx := context.Background()
+	w := g.object(path).NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("gcs store: writing %q: %w", path, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("gcs store: writing %q: %w", path, err)
+	}
+	return nil
+}
+
+func (g *GCS) List(prefix string) ([]string, error) {
+	ctx := context.Background()
+	it := g.client.Bucket(g.bucket).Objects(ctx, &gcstorage.Query{Prefix: g.key(prefix)})
+	var paths []string
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gcs store: listing %q: %w", prefix, err)
+		}
+		paths = append(paths, g.trimPrefix(attrs.Name))
+	}
+	return paths, nil
+}
+
+func (g *GCS) trimPrefix(key string) string {
+	if g.prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(key, g.prefix), "/")
+}
+
+func (g *GCS) Delete(path string) error {
+	ctx := context.Background()
+	if err := g.object(path).Delete(ctx); err != nil {
+		return fmt.Errorf("gcs store: deleting %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package local
+
+import (
+	"fmt"
+	"io/ioutil"
+	nurl "net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store"
+)
+
+// Local is a store.Store backed by the local filesystem.
+type Local struct {
+	root string
+}
+
+func init() {
+	store.Register("file", &Local{})
+}
+
+func New(uri string) (*Local, error) {
+	u, err := nurl.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	// concat host and path to restore full path, host might be `.`
+	p := u.Host + u.Path
+	if len(p) == 0 {
+		p = uri
+	}
+	if p[0:1] == "." || p[0:1] != "/" {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		p = abs
+	}
+	if runtime.GOOS == "windows" && p[0:1] == "/" {
+		p = strings.TrimPrefix(p, "/")
+	}
+
+	return &Local{root: p}, nil
+}
+
+func (l *Local) Open(uri string) (store.Store, error) {
+	return New(uri)
+}
+
+func (l *Local) EnsureDir(path string) error {
+	return os.MkdirAll(filepath.Join(l.root, path), 0744)
+}
+
+func (l *Local) Read(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(l.root, path))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", path, store.ErrNotExist)
+	}
+	return data, err
+}
+
+func (l *Local) Write(path string, data []byte) error {
+	fullPath := filepath.Join(l.root, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0744); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fullPath, data, 0644)
+}
+
+func (l *Local) List(prefix string) ([]string, error) {
+	var paths []string
+	root := filepath.Join(l.root, prefix)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func (l *Local) Delete(path string) error {
+	err := os.Remove(filepath.Join(l.root, path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
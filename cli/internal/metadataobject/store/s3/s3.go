@@ -0,0 +1,127 @@
+// Package s3 implements a store.Store backed by an S3 bucket, addressed by a
+// URI of the form s3://bucket/prefix.
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	nurl "net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/hasura/graphql-engine/cli/v2/internal/metadataobject/store"
+)
+
+type S3 struct {
+	bucket string
+	prefix string
+
+	client     *s3.S3
+	uploader   *s3manager.Uploader
+	downloader *s3manager.Downloader
+}
+
+func init() {
+	store.Register("s3", &S3{})
+}
+
+func New(uri string) (*S3, error) {
+	u, err := nurl.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("s3 store: %q is missing a bucket name", uri)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return nil, fmt.Errorf("s3 store: creating session: %w", err)
+	}
+
+	client := s3.New(sess)
+	return &S3{
+		bucket:     u.Host,
+		prefix:     strings.Trim(u.Path, "/"),
+		client:     client,
+		uploader:   s3manager.NewUploaderWithClient(client),
+		downloader: s3manager.NewDownloaderWithClient(client),
+	}, nil
+}
+
+func (s *S3) Open(uri string) (store.Store, error) {
+	return New(uri)
+}
+
+func (s *S3) key(path string) string {
+	if s.prefix == "" {
+		return path
+	}
+	return s.prefix + "/" + path
+}
+
+func (s *S3) Read(path string) ([]byte, error) {
+	buf := aws.NewWriteAtBuffer([]byte{})
+	_, err := s.downloader.Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(path)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound") {
+			return nil, fmt.Errorf("s3 store: reading %q: %w", path, store.ErrNotExist)
+		}
+		return nil, fmt.Errorf("s3 store: reading %q: %w", path, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *S3) Write(path string, data []byte) error {
+	_, err := s.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(path)),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 store: writing %q: %w", path, err)
+	}
+	return nil
+}
+
+func (s *S3) List(prefix string) ([]string, error) {
+	var paths []string
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.key(prefix)),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			paths = append(paths, s.trimPrefix(aws.StringValue(obj.Key)))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 store: listing %q: %w", prefix, err)
+	}
+	return paths, nil
+}
+
+func (s *S3) trimPrefix(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(key, s.prefix), "/")
+}
+
+func (s *S3) Delete(path string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(path)),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 store: deleting %q: %w", path, err)
+	}
+	return nil
+}